@@ -0,0 +1,95 @@
+package krouter
+
+import (
+	"net/http"
+	"sync"
+)
+
+// @Author KHighness
+// @Update 2022-11-14
+
+// HandlerFunc is the Context-based alternative to http.HandlerFunc. A
+// HandlerFunc also doubles as Context-based middleware: it calls ctx.Next()
+// to invoke the next handler in the chain.
+type HandlerFunc func(ctx *Context)
+
+// Param is a single matched route parameter.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Context is acquired from a pool in Router.ServeHTTP and released once the
+// handler chain returns, so routing a request allocates nothing beyond the
+// params slices captured during matching.
+type Context struct {
+	Writer  http.ResponseWriter
+	Request *http.Request
+
+	paramKeys   []string
+	paramValues []string
+	routePath   string
+
+	handlers []HandlerFunc
+	index    int
+}
+
+var contextPool = sync.Pool{
+	New: func() interface{} { return new(Context) },
+}
+
+// acquireContext fetches a Context from the pool and resets it for req.
+func acquireContext(w http.ResponseWriter, req *http.Request) *Context {
+	ctx := contextPool.Get().(*Context)
+	ctx.Writer = w
+	ctx.Request = req
+	ctx.paramKeys = ctx.paramKeys[:0]
+	ctx.paramValues = ctx.paramValues[:0]
+	ctx.routePath = ""
+	ctx.handlers = ctx.handlers[:0]
+	ctx.index = -1
+	return ctx
+}
+
+// releaseContext returns ctx to the pool.
+func releaseContext(ctx *Context) {
+	ctx.Writer = nil
+	ctx.Request = nil
+	contextPool.Put(ctx)
+}
+
+// Param returns the value of the route param named name, or "" if it was
+// not captured for the current request.
+func (c *Context) Param(name string) string {
+	for i, key := range c.paramKeys {
+		if key == name {
+			return c.paramValues[i]
+		}
+	}
+	return ""
+}
+
+// Params returns every route param captured for the current request.
+func (c *Context) Params() []Param {
+	params := make([]Param, len(c.paramKeys))
+	for i, key := range c.paramKeys {
+		params[i] = Param{Key: key, Value: c.paramValues[i]}
+	}
+	return params
+}
+
+// RoutePath returns the registered pattern the current request matched,
+// without its leading '/' (e.g. "user/:id" for a route registered as
+// "/user/:id"), useful as a low-cardinality label for metrics.
+func (c *Context) RoutePath() string {
+	return c.routePath
+}
+
+// Next invokes the next handler in the chain, if any. Middleware calls Next
+// to continue the chain; a handler that never calls Next stops it there.
+func (c *Context) Next() {
+	c.index++
+	if c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+	}
+}
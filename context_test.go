@@ -0,0 +1,80 @@
+package krouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// @Author KHighness
+// @Update 2022-11-15
+
+func TestContextNextChainsMiddlewareInOrder(t *testing.T) {
+	var ran []string
+	r := New()
+	r.UseFunc(func(ctx *Context) {
+		ran = append(ran, "outer")
+		ctx.Next()
+	})
+	r.UseFunc(func(ctx *Context) {
+		ran = append(ran, "inner")
+		ctx.Next()
+	})
+	r.GetFunc("/x", func(ctx *Context) {
+		ran = append(ran, "handler")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/x", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	want := []string{"outer", "inner", "handler"}
+	if len(ran) != len(want) {
+		t.Fatalf("ran = %v, want %v", ran, want)
+	}
+	for i := range want {
+		if ran[i] != want[i] {
+			t.Fatalf("ran = %v, want %v", ran, want)
+		}
+	}
+}
+
+func TestContextNextStopsChainWhenMiddlewareOmitsIt(t *testing.T) {
+	var ran []string
+	r := New()
+	r.UseFunc(func(ctx *Context) {
+		ran = append(ran, "auth")
+		ctx.Writer.WriteHeader(http.StatusUnauthorized)
+		// Deliberately does not call ctx.Next(): the chain must stop here.
+	})
+	r.GetFunc("/x", func(ctx *Context) {
+		ran = append(ran, "handler")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/x", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if len(ran) != 1 || ran[0] != "auth" {
+		t.Fatalf("expected chain to stop after auth, got %v", ran)
+	}
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestContextParamAndRoutePath(t *testing.T) {
+	r := New()
+	r.GetFunc("/user/:id", func(ctx *Context) {
+		if got := ctx.Param("id"); got != "42" {
+			t.Errorf("Param(id) = %q, want 42", got)
+		}
+		if got := ctx.RoutePath(); got != "user/:id" {
+			t.Errorf("RoutePath() = %q, want user/:id", got)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/user/42", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+}
@@ -7,11 +7,6 @@ import (
 // @Author KHighness
 // @Update 2022-11-09
 
-// GetParam returns route param stored in http.Request.
-func GetParam(r *http.Request, key string) string {
-	return GetAllParams(r)[key]
-}
-
 // contextKeyType defines a type which is used for
 // storing values in context.Context.
 type contextKeyType struct {
@@ -25,11 +20,37 @@ var contextKey = contextKeyType{}
 // store route params.
 type paramsMapType map[string]string
 
+// matchedParams holds the params captured while matching a request,
+// as parallel slices so the hot path never allocates a map.
+type matchedParams struct {
+	keys   []string
+	values []string
+}
+
+// GetParam returns route param stored in http.Request.
+func GetParam(r *http.Request, key string) string {
+	params, ok := r.Context().Value(contextKey).(*matchedParams)
+	if !ok {
+		return ""
+	}
+	for i, k := range params.keys {
+		if k == key {
+			return params.values[i]
+		}
+	}
+	return ""
+}
+
 // GetAllParams returns all route params sored in http.Request.
 func GetAllParams(r *http.Request) paramsMapType {
-	if values, ok := r.Context().Value(contextKey).(paramsMapType); ok {
-		return values
+	params, ok := r.Context().Value(contextKey).(*matchedParams)
+	if !ok {
+		return nil
 	}
 
-	return nil
+	values := make(paramsMapType, len(params.keys))
+	for i, k := range params.keys {
+		values[k] = params.values[i]
+	}
+	return values
 }
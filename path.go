@@ -0,0 +1,28 @@
+package krouter
+
+import (
+	stdpath "path"
+)
+
+// @Author KHighness
+// @Update 2022-11-12
+
+// CleanPath returns the canonical form of p: duplicate slashes are
+// collapsed, "." and ".." segments are resolved, and the result is always
+// rooted at "/". A trailing slash in p (other than the root itself) is
+// preserved, since krouter treats "/x" and "/x/" as distinct routes.
+func CleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+
+	trailingSlash := len(p) > 1 && p[len(p)-1] == '/'
+	cleaned := stdpath.Clean(p)
+	if trailingSlash && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 )
@@ -30,13 +31,13 @@ var (
 
 // methods enumerates all the valid http methods.
 var methods = map[string]struct{}{
-	http.MethodGet:    {},
-	http.MethodPost:   {},
-	http.MethodPut:    {},
-	http.MethodDelete: {},
-	http.MethodPatch:  {},
-	http.MethodHead:   {},
-	// http.MethodOptions: {},
+	http.MethodGet:     {},
+	http.MethodPost:    {},
+	http.MethodPut:     {},
+	http.MethodDelete:  {},
+	http.MethodPatch:   {},
+	http.MethodHead:    {},
+	http.MethodOptions: {},
 }
 
 // Middleware defines a function which is used for web middleware.
@@ -51,13 +52,32 @@ type Parameters struct {
 // records any URL params, and executes an end handler.
 type Router struct {
 	prefix string
-	// middleware records the middleware stack
+	// middleware records the http.HandlerFunc-based middleware stack.
 	middleware []Middleware
-	// tree routers whose key is method and value is Tree.
-	trees      map[string]*Tree
-	parameters Parameters
+	// ctxMiddleware records the Context-based middleware stack, applied to
+	// routes registered through HandleFunc and its GetFunc/PostFunc/... sugar.
+	ctxMiddleware []HandlerFunc
+	// tree is the single radix tree shared by every HTTP method.
+	tree *Tree
+	// customMethods records verbs registered through RegisterMethod, in
+	// addition to the well-known methods in the methods set.
+	customMethods map[string]struct{}
+	parameters    Parameters
 	// notFound is a custom handler for not-found route
 	notFound http.HandlerFunc
+	// methodNotAllowed is a custom handler for a route whose path exists
+	// but has no handler registered for the request method.
+	methodNotAllowed http.HandlerFunc
+	// DefaultOPTIONSHandler, when set, overrides the built-in OPTIONS
+	// responder used to auto-synthesize OPTIONS responses; it receives the
+	// methods actually registered on the matched route.
+	DefaultOPTIONSHandler func(w http.ResponseWriter, r *http.Request, allowed []string)
+	// RedirectTrailingSlash, if true, redirects a request whose path only
+	// differs from a registered route by a trailing slash to that route.
+	RedirectTrailingSlash bool
+	// RedirectFixedPath, if true, redirects a request whose CleanPath(path)
+	// is registered (but whose raw path is not) to the cleaned path.
+	RedirectFixedPath bool
 	// PanicHandler handles panic.
 	PanicHandler func(w http.ResponseWriter, r *http.Request, err interface{})
 }
@@ -65,31 +85,95 @@ type Router struct {
 // New creates a Router.
 func New() *Router {
 	return &Router{
-		trees: make(map[string]*Tree),
+		tree: NewTree(),
 	}
 }
 
+// RegisterMethod allows method to be used with Handle, for custom HTTP
+// verbs beyond the well-known set (analogous to chi's MethodFunc).
+func (r *Router) RegisterMethod(method string) {
+	if _, ok := methods[method]; ok {
+		return
+	}
+	if r.customMethods == nil {
+		r.customMethods = make(map[string]struct{})
+	}
+	r.customMethods[method] = struct{}{}
+}
+
+// isValidMethod reports whether method is a well-known method or was
+// registered through RegisterMethod.
+func (r *Router) isValidMethod(method string) bool {
+	if _, ok := methods[method]; ok {
+		return true
+	}
+	_, ok := r.customMethods[method]
+	return ok
+}
+
 // Handle registers a new http.HandlerFunc with the given path and method.
 func (r *Router) Handle(method string, path string, handle http.HandlerFunc) {
-	if _, ok := methods[method]; !ok {
+	if !r.isValidMethod(method) {
 		panic("invalid method: " + method)
 	}
 
-	tree, ok := r.trees[method]
-	if !ok {
-		tree = NewTree()
-		r.trees[method] = tree
+	if r.prefix != "" {
+		path = r.prefix + "/" + strings.TrimPrefix(path, "/")
+	}
+
+	if routeName := r.parameters.routeName; routeName != "" {
+		r.tree.parameters.routeName = routeName
+	}
+
+	r.tree.Register(method, path, handle, r.middleware...)
+}
+
+// HandleFunc registers a Context-based HandlerFunc with the given path and
+// method, as an alternative to Handle's http.HandlerFunc.
+func (r *Router) HandleFunc(method string, path string, handle HandlerFunc) {
+	if !r.isValidMethod(method) {
+		panic("invalid method: " + method)
 	}
 
 	if r.prefix != "" {
-		path = r.prefix + "/" + path
+		path = r.prefix + "/" + strings.TrimPrefix(path, "/")
 	}
 
 	if routeName := r.parameters.routeName; routeName != "" {
-		tree.parameters.routeName = routeName
+		r.tree.parameters.routeName = routeName
 	}
 
-	tree.Register(path, handle, r.middleware...)
+	r.tree.RegisterCtx(method, path, handle, r.ctxMiddleware...)
+}
+
+// GetFunc is HandleFunc for the GET method.
+func (r *Router) GetFunc(path string, handle HandlerFunc) {
+	r.HandleFunc(http.MethodGet, path, handle)
+}
+
+// PostFunc is HandleFunc for the POST method.
+func (r *Router) PostFunc(path string, handle HandlerFunc) {
+	r.HandleFunc(http.MethodPost, path, handle)
+}
+
+// PutFunc is HandleFunc for the PUT method.
+func (r *Router) PutFunc(path string, handle HandlerFunc) {
+	r.HandleFunc(http.MethodPut, path, handle)
+}
+
+// DeleteFunc is HandleFunc for the DELETE method.
+func (r *Router) DeleteFunc(path string, handle HandlerFunc) {
+	r.HandleFunc(http.MethodDelete, path, handle)
+}
+
+// PatchFunc is HandleFunc for the PATCH method.
+func (r *Router) PatchFunc(path string, handle HandlerFunc) {
+	r.HandleFunc(http.MethodPatch, path, handle)
+}
+
+// HeadFunc is HandleFunc for the HEAD method.
+func (r *Router) HeadFunc(path string, handle HandlerFunc) {
+	r.HandleFunc(http.MethodHead, path, handle)
 }
 
 // Get adds the route `path` which matches a GET http method to execute the `handle` function.
@@ -161,26 +245,114 @@ func (r *Router) HEADAndName(path string, handle http.HandlerFunc, routeName str
 // Group creates a router group if there is a prefix that uses prefix.
 func (r *Router) Group(prefix string) *Router {
 	return &Router{
-		prefix:     prefix,
-		trees:      r.trees,
-		middleware: r.middleware,
+		prefix:        prefix,
+		tree:          r.tree,
+		middleware:    r.middleware,
+		ctxMiddleware: r.ctxMiddleware,
+		customMethods: r.customMethods,
+	}
+}
+
+// mountedRouter remembers a *Router attached via Mount, together with the
+// prefix it was mounted at, so Generate can resolve routes registered on it.
+type mountedRouter struct {
+	prefix string
+	router *Router
+}
+
+// Mount attaches handler at prefix: any request whose path is prefix, or
+// starts with prefix+"/", is delegated to handler with prefix stripped from
+// req.URL.Path. handler may be another *Router, in which case its named
+// routes remain reachable through Generate on the outer router.
+func (r *Router) Mount(prefix string, handler http.Handler) {
+	prefix = joinPrefix(r.prefix, prefix)
+
+	fullPrefix := "/" + prefix
+	mountHandle := func(w http.ResponseWriter, req *http.Request) {
+		sub := new(http.Request)
+		*sub = *req
+		sub.URL = new(url.URL)
+		*sub.URL = *req.URL
+		sub.URL.Path = strings.TrimPrefix(req.URL.Path, fullPrefix)
+		if sub.URL.Path == "" || sub.URL.Path[0] != '/' {
+			sub.URL.Path = "/" + sub.URL.Path
+		}
+		handler.ServeHTTP(w, sub)
+	}
+
+	exact := r.tree.root.insert(trimPathPrefix(prefix))
+	exact.path = prefix
+	exact.mounted = mountHandle
+
+	withCatchAll := trimPathPrefix(prefix + "/*krouterMountPath")
+	any := r.tree.root.insert(withCatchAll)
+	any.path = withCatchAll
+	any.mounted = mountHandle
+	if n := countParams(withCatchAll); n > r.tree.maxParams {
+		r.tree.maxParams = n
+	}
+
+	if sub, ok := handler.(*Router); ok {
+		r.tree.mountedRouters = append(r.tree.mountedRouters, mountedRouter{prefix: prefix, router: sub})
+	}
+}
+
+// Route builds a child Router scoped to prefix and passes it to fn so
+// routes can be registered on it. The child inherits a snapshot of r's
+// middleware stack but owns an independent slice, so calling sub.Use(...)
+// inside fn does not affect r or any of r's other children. Routes
+// registered on the child are added directly to r's tree with the
+// combined prefix, so Generate resolves them through r as usual.
+func (r *Router) Route(prefix string, fn func(r *Router)) *Router {
+	sub := &Router{
+		prefix:        joinPrefix(r.prefix, prefix),
+		tree:          r.tree,
+		middleware:    append([]Middleware(nil), r.middleware...),
+		ctxMiddleware: append([]HandlerFunc(nil), r.ctxMiddleware...),
+		customMethods: r.customMethods,
+	}
+	fn(sub)
+	return sub
+}
+
+// joinPrefix combines a parent prefix and a child prefix into a single
+// slash-separated prefix with no leading or trailing slash.
+func joinPrefix(base, prefix string) string {
+	base = strings.Trim(base, "/")
+	prefix = strings.Trim(prefix, "/")
+	switch {
+	case base == "":
+		return prefix
+	case prefix == "":
+		return base
+	default:
+		return base + "/" + prefix
 	}
 }
 
 // Generate returns reverse routing by method, routeName and params.
 func (r *Router) Generate(method string, routeName string, params map[string]string) (string, error) {
-	tree, ok := r.trees[method]
-	if !ok {
-		return "", ErrMethodNotFound
+	if node, ok := r.tree.routes[routeName]; ok {
+		if node.methods == nil || node.methods.handlerFor(method) == nil {
+			return "", ErrMethodNotFound
+		}
+		return generatePath(node.path, params)
 	}
 
-	route, ok := tree.routes[routeName]
-	if !ok {
-		return "", ErrRouteNotFound
+	for _, m := range r.tree.mountedRouters {
+		if route, err := m.router.Generate(method, routeName, params); err == nil {
+			return "/" + m.prefix + route, nil
+		}
 	}
 
+	return "", ErrRouteNotFound
+}
+
+// generatePath substitutes params into pattern's ":name"/"{name:regex}"
+// segments, validating each against its pattern.
+func generatePath(pattern string, params map[string]string) (string, error) {
 	var segments []string
-	list := splitPattern(route.path)
+	list := splitPattern(pattern)
 	for _, segment := range list {
 		if string(segment[0]) == ":" {
 			key := params[string(segment[1:])]
@@ -223,15 +395,26 @@ func (r *Router) Use(middleware ...Middleware) {
 	}
 }
 
+// UseFunc appends Context-based middleware to the ctx middleware stack.
+func (r *Router) UseFunc(middleware ...HandlerFunc) {
+	if len(middleware) > 0 {
+		r.ctxMiddleware = append(r.ctxMiddleware, middleware...)
+	}
+}
+
 // NotFoundFunc registers a custom handler when the request route is not found.
 func (r *Router) NotFoundFunc(handler http.HandlerFunc) {
 	r.notFound = handler
 }
 
+// MethodNotAllowedFunc registers a custom handler for a route whose path
+// exists but has no handler registered for the request method.
+func (r *Router) MethodNotAllowedFunc(handler http.HandlerFunc) {
+	r.methodNotAllowed = handler
+}
+
 // ServeHTTP implements http.Handler.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	requestURL := req.URL.Path
-
 	if r.PanicHandler != nil {
 		defer func() {
 			if err := recover(); err != nil {
@@ -240,43 +423,103 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		}()
 	}
 
-	if _, ok := r.trees[req.Method]; !ok {
+	node, keys, values, matched := r.tree.Match(req.URL.Path, nil, nil)
+	if !matched {
+		if r.redirect(w, req, req.URL.Path) {
+			return
+		}
 		r.HandleNotFound(w, req, r.middleware)
 		return
 	}
 
-	nodes := r.trees[req.Method].Search(requestURL, false)
-	if len(nodes) > 0 {
-		node := nodes[0]
+	var route *routeMethod
+	if node.methods != nil {
+		route = node.methods.handlerFor(req.Method)
+	}
+	if route == nil {
+		if node.mounted != nil {
+			node.mounted(w, req)
+			return
+		}
+		if node.methods == nil {
+			r.HandleNotFound(w, req, r.middleware)
+			return
+		}
+		if req.Method == http.MethodOptions {
+			r.handleOPTIONS(w, req, node.methods)
+			return
+		}
+		r.handleMethodNotAllowed(w, req, node.methods)
+		return
+	}
 
-		if node.handle != nil {
-			if node.path == requestURL {
-				handle(w, req, node.handle, node.middleware)
-				return
-			}
-			if node.path == requestURL[1:] {
-				handle(w, req, node.handle, node.middleware)
-			}
+	if route.ctxHandle != nil {
+		ctx := acquireContext(w, req)
+		ctx.routePath = node.path
+		ctx.paramKeys = append(ctx.paramKeys, keys...)
+		ctx.paramValues = append(ctx.paramValues, values...)
+		ctx.handlers = append(ctx.handlers, route.ctxMiddleware...)
+		ctx.handlers = append(ctx.handlers, route.ctxHandle)
+		ctx.Next()
+		releaseContext(ctx)
+		return
+	}
+
+	if len(keys) > 0 {
+		reqCtx := context.WithValue(req.Context(), contextKey, &matchedParams{keys: keys, values: values})
+		req = req.WithContext(reqCtx)
+	}
+
+	handle(w, req, route.handle, route.middleware)
+}
+
+// redirect looks for a registered route that only differs from req.URL.Path
+// by path cleaning or a trailing slash, and if one exists, writes a redirect
+// to it and reports true. It never redirects to a path that is itself
+// unregistered, so a request that cannot be served never loops.
+func (r *Router) redirect(w http.ResponseWriter, req *http.Request, path string) bool {
+	if r.RedirectFixedPath {
+		if cleaned := CleanPath(path); cleaned != path && r.pathRegistered(cleaned) {
+			r.writeRedirect(w, req, cleaned)
+			return true
 		}
 	}
 
-	if len(nodes) == 0 {
-		list := strings.Split(requestURL, "/")
-		prefix := list[1]
-		nodes := r.trees[req.Method].Search(prefix, true)
-		for _, node := range nodes {
-			if handler := node.handle; handler != nil && node.path != requestURL {
-				if matchParamsMap, ok := r.matchAndParse(requestURL, node.path); ok {
-					ctx := context.WithValue(req.Context(), contextKey, matchParamsMap)
-					req = req.WithContext(ctx)
-					handle(w, req, handler, node.middleware)
-					return
-				}
-			}
+	if r.RedirectTrailingSlash {
+		var alt string
+		if strings.HasSuffix(path, "/") {
+			alt = strings.TrimSuffix(path, "/")
+		} else {
+			alt = path + "/"
+		}
+		if alt != "" && r.pathRegistered(alt) {
+			r.writeRedirect(w, req, alt)
+			return true
 		}
 	}
 
-	r.HandleNotFound(w, req, r.middleware)
+	return false
+}
+
+// pathRegistered reports whether path matches a registered route.
+func (r *Router) pathRegistered(path string) bool {
+	keys := make([]string, 0, r.tree.maxParams)
+	values := make([]string, 0, r.tree.maxParams)
+	_, _, _, matched := r.tree.Match(path, keys, values)
+	return matched
+}
+
+// writeRedirect redirects req to path, using 301 for GET/HEAD (safe to
+// re-issue) and 308 for other methods so the request body is preserved.
+func (r *Router) writeRedirect(w http.ResponseWriter, req *http.Request, path string) {
+	code := http.StatusMovedPermanently
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+
+	u := *req.URL
+	u.Path = path
+	http.Redirect(w, req, u.String(), code)
 }
 
 // HandleNotFound registers a handler when the request route is not found.
@@ -289,6 +532,30 @@ func (r *Router) HandleNotFound(w http.ResponseWriter, req *http.Request, middle
 	http.NotFound(w, req)
 }
 
+// handleMethodNotAllowed responds 405 with an Allow header computed from
+// the methods actually registered on the matched node.
+func (r *Router) handleMethodNotAllowed(w http.ResponseWriter, req *http.Request, rm *routeMethods) {
+	w.Header().Set("Allow", strings.Join(rm.allowed(), ", "))
+	if r.methodNotAllowed != nil {
+		handle(w, req, r.methodNotAllowed, r.middleware)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// handleOPTIONS auto-synthesizes an OPTIONS response listing the methods
+// registered on the matched node; ServeHTTP calls it only when no explicit
+// OPTIONS handler was registered for that node.
+func (r *Router) handleOPTIONS(w http.ResponseWriter, req *http.Request, rm *routeMethods) {
+	allowed := rm.allowed()
+	if r.DefaultOPTIONSHandler != nil {
+		r.DefaultOPTIONSHandler(w, req, allowed)
+		return
+	}
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	w.WriteHeader(http.StatusOK)
+}
+
 // handle executes middleware chain.
 func handle(w http.ResponseWriter, req *http.Request, handler http.HandlerFunc, middleware []Middleware) {
 	var baseHandler = handler
@@ -298,64 +565,3 @@ func handle(w http.ResponseWriter, req *http.Request, handler http.HandlerFunc,
 
 	baseHandler(w, req)
 }
-
-// Match checks if the request matches the route pattern.
-func (r *Router) Match(requestURL string, path string) bool {
-	_, ok := r.matchAndParse(requestURL, path)
-	return ok
-}
-
-// matchAndParse checks if the request matches the route and returns a map of the parse ones,
-func (r *Router) matchAndParse(requestURL string, path string) (matchParams paramsMapType, b bool) {
-	var (
-		matchName []string
-		pattern   string
-	)
-
-	b = true
-	matchParams = make(paramsMapType)
-
-	list := strings.Split(path, "/")
-	for _, str := range list {
-		if str == "" {
-			continue
-		}
-
-		strLen := len(str)
-		firstChar := str[0]
-		lastChar := str[strLen-1]
-		if string(firstChar) == "{" && string(lastChar) == "}" {
-			matchStr := string(str[1 : strLen-1])
-			list := strings.Split(matchStr, ":")
-			pattern = pattern + "/" + "(" + list[1] + ")"
-		} else if string(firstChar) == ":" {
-			matchStr := str
-			list := strings.Split(matchStr, ":")
-			matchName = append(matchName, list[1])
-			if list[1] == idKey {
-				pattern = pattern + "/" + "(" + idPattern + ")"
-			} else {
-				pattern = pattern + "/" + "(" + defaultPattern + ")"
-			}
-		} else {
-			pattern = pattern + "/" + str
-		}
-	}
-
-	if strings.HasSuffix(requestURL, "/") {
-		pattern = pattern + "/"
-	}
-
-	regex := regexp.MustCompile(pattern)
-	if subMatch := regex.FindSubmatch([]byte(requestURL)); subMatch != nil {
-		if string(subMatch[0]) == requestURL {
-			subMatch = subMatch[:1]
-			for k, v := range subMatch {
-				matchParams[matchName[k]] = string(v)
-			}
-			return
-		}
-	}
-
-	return nil, false
-}
@@ -0,0 +1,191 @@
+package krouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// @Author KHighness
+// @Update 2022-11-16
+
+func TestMethodNotAllowedSetsAllowHeader(t *testing.T) {
+	r := New()
+	r.Get("/user", func(w http.ResponseWriter, req *http.Request) {})
+	r.Post("/user", func(w http.ResponseWriter, req *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodDelete, "/user", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Code = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+	allow := w.Header().Get("Allow")
+	if !strings.Contains(allow, http.MethodGet) || !strings.Contains(allow, http.MethodPost) {
+		t.Fatalf("Allow header = %q, want it to list GET and POST", allow)
+	}
+}
+
+func TestMountDispatchesExactPrefixAndCatchAll(t *testing.T) {
+	var gotPath string
+	sub := New()
+	sub.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		gotPath = req.URL.Path
+	})
+	sub.Get("/widget", func(w http.ResponseWriter, req *http.Request) {
+		gotPath = req.URL.Path
+	})
+
+	root := New()
+	root.Mount("/api", sub)
+
+	cases := []struct{ reqPath, wantSubPath string }{
+		{"/api", "/"},
+		{"/api/widget", "/widget"},
+	}
+	for _, c := range cases {
+		gotPath = ""
+		req := httptest.NewRequest(http.MethodGet, c.reqPath, nil)
+		w := httptest.NewRecorder()
+		root.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: Code = %d, want %d", c.reqPath, w.Code, http.StatusOK)
+		}
+		if gotPath != c.wantSubPath {
+			t.Errorf("%s: sub-router saw path %q, want %q", c.reqPath, gotPath, c.wantSubPath)
+		}
+	}
+}
+
+func TestRouteMiddlewareIsolatedFromParent(t *testing.T) {
+	var ran []string
+	r := New()
+	r.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			ran = append(ran, "root")
+			next(w, req)
+		}
+	})
+
+	r.Route("/admin", func(sub *Router) {
+		sub.Use(func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request) {
+				ran = append(ran, "admin")
+				next(w, req)
+			}
+		})
+		sub.Get("/ping", func(w http.ResponseWriter, req *http.Request) {
+			ran = append(ran, "handler")
+		})
+	})
+	r.Get("/ping", func(w http.ResponseWriter, req *http.Request) {
+		ran = append(ran, "handler")
+	})
+
+	ran = nil
+	req := httptest.NewRequest(http.MethodGet, "/admin/ping", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if want := []string{"admin", "root", "handler"}; !equalStrings(ran, want) {
+		t.Fatalf("/admin/ping: ran = %v, want %v", ran, want)
+	}
+
+	ran = nil
+	req = httptest.NewRequest(http.MethodGet, "/ping", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if want := []string{"root", "handler"}; !equalStrings(ran, want) {
+		t.Fatalf("/ping: ran = %v, want %v (admin middleware must not leak onto root routes)", ran, want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRedirectTrailingSlashUses301ForGetAnd308ForPost(t *testing.T) {
+	r := New()
+	r.RedirectTrailingSlash = true
+	r.Get("/user", func(w http.ResponseWriter, req *http.Request) {})
+	r.Post("/user", func(w http.ResponseWriter, req *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/user/", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("GET /user/: Code = %d, want %d", w.Code, http.StatusMovedPermanently)
+	}
+	if loc := w.Header().Get("Location"); loc != "/user" {
+		t.Errorf("GET /user/: Location = %q, want /user", loc)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/user/", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusPermanentRedirect {
+		t.Errorf("POST /user/: Code = %d, want %d", w.Code, http.StatusPermanentRedirect)
+	}
+}
+
+func TestRedirectFixedPathCleansDuplicateSlashes(t *testing.T) {
+	r := New()
+	r.RedirectFixedPath = true
+	r.Get("/user/list", func(w http.ResponseWriter, req *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/user//list", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMovedPermanently {
+		t.Fatalf("Code = %d, want %d", w.Code, http.StatusMovedPermanently)
+	}
+	if loc := w.Header().Get("Location"); loc != "/user/list" {
+		t.Fatalf("Location = %q, want /user/list", loc)
+	}
+}
+
+func TestRedirectNeverLoopsToAnUnregisteredAlt(t *testing.T) {
+	r := New()
+	r.RedirectTrailingSlash = true
+	r.Get("/user/", func(w http.ResponseWriter, req *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/user", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusMovedPermanently {
+		t.Fatalf("Code = %d, want %d", w.Code, http.StatusMovedPermanently)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/nope", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Code = %d, want %d (unregistered alt must 404, not redirect)", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestOptionsAutoSynthesizesAllowHeader(t *testing.T) {
+	r := New()
+	r.Get("/user", func(w http.ResponseWriter, req *http.Request) {})
+	r.Post("/user", func(w http.ResponseWriter, req *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodOptions, "/user", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Code = %d, want %d", w.Code, http.StatusOK)
+	}
+	allow := w.Header().Get("Allow")
+	if !strings.Contains(allow, http.MethodGet) || !strings.Contains(allow, http.MethodPost) {
+		t.Fatalf("Allow header = %q, want it to list GET and POST", allow)
+	}
+}
@@ -2,142 +2,438 @@ package krouter
 
 import (
 	"net/http"
+	"regexp"
 	"strings"
 )
 
 // @Author KHighness
 // @Update 2022-11-08
 
-// Tree is a trie tree.
+// nodeType distinguishes the kind of path segment a Node matches.
+type nodeType uint8
+
+const (
+	// ntStatic matches a literal, byte-for-byte prefix.
+	ntStatic nodeType = iota
+	// ntParam matches a single ":name" path segment.
+	ntParam
+	// ntRegexp matches a single "{name:pattern}" path segment.
+	ntRegexp
+	// ntCatchAll matches a "*name" segment and everything after it.
+	ntCatchAll
+)
+
+// Tree is a radix tree used for route registration and matching.
 type Tree struct {
 	root       *Node
 	parameters Parameters
 	routes     map[string]*Node
+	// maxParams is the largest number of params any registered route can
+	// produce, used to pre-size the params slices passed to Match. Router's
+	// ServeHTTP does not use it: it passes nil slices, so matching a route
+	// with params allocates them.
+	maxParams int
+	// mountedRouters records the *Router handlers attached via Mount across
+	// every *Router sharing this tree (including Group/Route children), so
+	// Generate can resolve named routes registered on a mounted subrouter
+	// regardless of which child Router performed the Mount.
+	mountedRouters []mountedRouter
 }
 
-// Node records any URL params, and executes an end handler.
+// Node is a single radix tree node. Static nodes are compressed: a node's
+// prefix can span several bytes, and children are indexed by their first
+// byte (label) so matching never needs to scan siblings.
 type Node struct {
-	// key records Node's key
-	key string
+	nType nodeType
+
+	// label is the first byte of prefix, used as the key in the parent's
+	// children map.
+	label byte
+	// prefix is the literal byte string this node consumes (ntStatic only).
+	prefix string
+
+	// children holds static children indexed by their label byte.
+	children map[byte]*Node
+	// paramChild is the single ":name" child, if any.
+	paramChild *Node
+	// regexChildren holds "{name:pattern}" children; unlike static children
+	// they cannot be indexed by label, so they are tried in order.
+	regexChildren []*Node
+	// anyChild is the single "*name" catch-all child, if any.
+	anyChild *Node
 
-	// path records a request uri
+	// paramName is set for ntParam, ntRegexp and ntCatchAll nodes.
+	paramName string
+	// regex is the pre-compiled pattern for an ntRegexp node, compiled once
+	// at Register time so matching never re-compiles a regexp.
+	regex *regexp.Regexp
+
+	// path records the registered pattern, without its leading '/'.
 	path string
+	// methods holds the per-HTTP-method handlers registered at this exact
+	// node. A nil methods means no route terminates here.
+	methods *routeMethods
+	// mounted is set by Router.Mount: it handles every request under this
+	// node regardless of method, delegating to an arbitrary http.Handler.
+	mounted http.HandlerFunc
+}
 
-	// handle is a function to process current path's request
-	handle http.HandlerFunc
+// routeMethod is a single method's handler and middleware stack. Exactly
+// one of (handle, ctxHandle) is set, depending on whether the route was
+// registered through Router.Handle or Router.HandleFunc.
+type routeMethod struct {
+	handle     http.HandlerFunc
+	middleware []Middleware
 
-	// depths records Node's depth
-	depth int
+	ctxHandle     HandlerFunc
+	ctxMiddleware []HandlerFunc
+}
 
-	// children records Node's children node
-	children map[string]*Node
+// routeMethods holds the handlers registered for a path, one slot per
+// well-known HTTP method plus a map for any custom verb registered through
+// Router.RegisterMethod.
+type routeMethods struct {
+	get, post, put, delete, patch, head, options *routeMethod
+	anyOther                                     map[string]*routeMethod
+}
 
-	// isEnd judges if Node is leaf
-	isEnd bool
+// handlerFor returns the routeMethod registered for method, or nil.
+func (rm *routeMethods) handlerFor(method string) *routeMethod {
+	switch method {
+	case http.MethodGet:
+		return rm.get
+	case http.MethodPost:
+		return rm.post
+	case http.MethodPut:
+		return rm.put
+	case http.MethodDelete:
+		return rm.delete
+	case http.MethodPatch:
+		return rm.patch
+	case http.MethodHead:
+		return rm.head
+	case http.MethodOptions:
+		return rm.options
+	default:
+		return rm.anyOther[method]
+	}
+}
 
-	// middleware records middleware stack
-	middleware []Middleware
+// set registers route as the handler for method.
+func (rm *routeMethods) set(method string, route *routeMethod) {
+	switch method {
+	case http.MethodGet:
+		rm.get = route
+	case http.MethodPost:
+		rm.post = route
+	case http.MethodPut:
+		rm.put = route
+	case http.MethodDelete:
+		rm.delete = route
+	case http.MethodPatch:
+		rm.patch = route
+	case http.MethodHead:
+		rm.head = route
+	case http.MethodOptions:
+		rm.options = route
+	default:
+		if rm.anyOther == nil {
+			rm.anyOther = make(map[string]*routeMethod)
+		}
+		rm.anyOther[method] = route
+	}
 }
 
-// NewNode creates a newly initialized Node.
-func NewNode(key string, depth int) *Node {
-	return &Node{
-		key:      key,
-		depth:    depth,
-		children: make(map[string]*Node),
+// allowed returns the methods registered on rm, for use in an Allow header.
+func (rm *routeMethods) allowed() []string {
+	var list []string
+	add := func(method string, route *routeMethod) {
+		if route != nil {
+			list = append(list, method)
+		}
+	}
+	add(http.MethodGet, rm.get)
+	add(http.MethodPost, rm.post)
+	add(http.MethodPut, rm.put)
+	add(http.MethodDelete, rm.delete)
+	add(http.MethodPatch, rm.patch)
+	add(http.MethodHead, rm.head)
+	add(http.MethodOptions, rm.options)
+	for method := range rm.anyOther {
+		list = append(list, method)
 	}
+	return list
 }
 
 // NewTree creates a newly initialized Tree.
 func NewTree() *Tree {
 	return &Tree{
-		root:   NewNode("/", 1),
+		root:   &Node{nType: ntStatic},
 		routes: make(map[string]*Node),
 	}
 }
 
-// Register adds a node to Tree.
-func (t *Tree) Register(pattern string, handle http.HandlerFunc, middleware ...Middleware) {
-	var currNode = t.root
-
-	if pattern != currNode.key {
-		pattern = trimPathPrefix(pattern)
-		keyList := splitPattern(pattern)
-		for _, key := range keyList {
-			node, ok := currNode.children[key]
-			if !ok {
-				node = NewNode(key, currNode.depth+1)
-				if len(middleware) > 0 {
-					node.middleware = append(node.middleware, middleware...)
-				}
-				currNode.children[key] = node
-			}
-			currNode = node
+// registerNode walks/creates the node for pattern and prepares it to carry
+// a route, common bookkeeping shared by Register and RegisterCtx.
+func (t *Tree) registerNode(pattern string) *Node {
+	pattern = trimPathPrefix(pattern)
+
+	node := t.root.insert(pattern)
+	node.path = pattern
+	if node.methods == nil {
+		node.methods = &routeMethods{}
+	}
+
+	if routeName := t.parameters.routeName; routeName != "" {
+		t.routes[routeName] = node
+		t.parameters.routeName = ""
+	}
+
+	if n := countParams(pattern); n > t.maxParams {
+		t.maxParams = n
+	}
+
+	return node
+}
+
+// Register adds a route to the tree, compiling any "{name:pattern}" segment
+// into a *regexp.Regexp once so matching never compiles on the hot path.
+func (t *Tree) Register(method string, pattern string, handle http.HandlerFunc, middleware ...Middleware) {
+	node := t.registerNode(pattern)
+	node.methods.set(method, &routeMethod{handle: handle, middleware: middleware})
+}
+
+// RegisterCtx is Register's Context-based equivalent, used by Router.HandleFunc.
+func (t *Tree) RegisterCtx(method string, pattern string, handle HandlerFunc, middleware ...HandlerFunc) {
+	node := t.registerNode(pattern)
+	node.methods.set(method, &routeMethod{ctxHandle: handle, ctxMiddleware: middleware})
+}
+
+// insert walks/creates the path from n down to the node representing path,
+// splitting static prefixes and branching into param/regexp/catch-all
+// children as wildcard markers are encountered.
+func (n *Node) insert(path string) *Node {
+	if path == "" {
+		return n
+	}
+
+	// A wildcard marker only counts at the start of a segment.
+	idx := -1
+	for i := 0; i < len(path) && idx == -1; i++ {
+		if (path[i] == ':' || path[i] == '{' || path[i] == '*') && (i == 0 || path[i-1] == '/') {
+			idx = i
 		}
 	}
+	if idx == -1 {
+		return n.insertStatic(path)
+	}
+
+	node := n
+	if idx > 0 {
+		node = n.insertStatic(path[:idx])
+	}
+	rest := path[idx:]
 
-	if len(middleware) > 0 && currNode.depth == 1 {
-		currNode.middleware = append(currNode.middleware, middleware...)
+	switch rest[0] {
+	case ':':
+		return node.insertParam(rest)
+	case '{':
+		return node.insertRegexp(rest)
+	default:
+		return node.insertCatchAll(rest)
 	}
+}
 
-	currNode.handle = handle
-	currNode.isEnd = true
-	currNode.path = pattern
+// insertStatic performs a classic compressed-radix insert of prefix,
+// splitting an existing child when prefix only partially matches it.
+func (n *Node) insertStatic(prefix string) *Node {
+	if prefix == "" {
+		return n
+	}
 
-	if routeName := t.parameters.routeName; routeName != "" {
-		t.routes[routeName] = currNode
+	label := prefix[0]
+	child, ok := n.children[label]
+	if !ok {
+		newNode := &Node{nType: ntStatic, label: label, prefix: prefix}
+		if n.children == nil {
+			n.children = make(map[byte]*Node)
+		}
+		n.children[label] = newNode
+		return newNode
 	}
+
+	common := commonPrefixLen(prefix, child.prefix)
+	if common == len(child.prefix) {
+		return child.insertStatic(prefix[common:])
+	}
+
+	split := &Node{
+		nType:    ntStatic,
+		label:    child.prefix[0],
+		prefix:   child.prefix[:common],
+		children: map[byte]*Node{child.prefix[common]: child},
+	}
+	child.prefix = child.prefix[common:]
+	child.label = child.prefix[0]
+	n.children[label] = split
+
+	if common == len(prefix) {
+		return split
+	}
+	return split.insertStatic(prefix[common:])
 }
 
-// Search returns nodes that the request matches the route pattern.
-func (t *Tree) Search(pattern string, isRegex bool) (nodes []*Node) {
-	var (
-		node  = t.root
-		queue []*Node
-	)
+// insertParam creates or reuses the ":name" child for rest, which starts
+// with ':', and continues inserting whatever follows the segment.
+func (n *Node) insertParam(rest string) *Node {
+	name, after := splitSegment(rest[1:])
+	if n.paramChild == nil {
+		n.paramChild = &Node{nType: ntParam, paramName: name}
+	}
+	return n.paramChild.insert(after)
+}
+
+// insertRegexp creates or reuses the "{name:pattern}" child for rest, which
+// starts with '{', and continues inserting whatever follows the segment.
+func (n *Node) insertRegexp(rest string) *Node {
+	end := strings.IndexByte(rest, '}')
+	if end == -1 {
+		panic("invalid pattern, missing closing '}': " + rest)
+	}
+	spec := rest[1:end]
+	after := rest[end+1:]
+
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 {
+		panic("invalid pattern, expected '{name:pattern}': " + rest[:end+1])
+	}
+	name, pattern := parts[0], parts[1]
 
-	if pattern == node.path {
-		nodes = append(nodes, node)
-		return
+	for _, child := range n.regexChildren {
+		if child.paramName == name && child.regex.String() == "^"+pattern+"$" {
+			return child.insert(after)
+		}
 	}
 
-	if !isRegex {
-		pattern = trimPathPrefix(pattern)
+	child := &Node{nType: ntRegexp, paramName: name, regex: regexp.MustCompile("^" + pattern + "$")}
+	n.regexChildren = append(n.regexChildren, child)
+	return child.insert(after)
+}
+
+// insertCatchAll creates or reuses the "*name" child for rest, which starts
+// with '*'. A catch-all always terminates the route.
+func (n *Node) insertCatchAll(rest string) *Node {
+	if n.anyChild == nil {
+		n.anyChild = &Node{nType: ntCatchAll, paramName: rest[1:]}
 	}
-	keyList := splitPattern(pattern)
+	return n.anyChild
+}
+
+// Match walks the tree byte-by-byte looking for a node registered for path,
+// falling back static -> regexp -> param -> catch-all at every branch.
+// keys/values should be pre-allocated with capacity t.maxParams.
+func (t *Tree) Match(path string, keys, values []string) (*Node, []string, []string, bool) {
+	return t.root.match(trimPathPrefix(path), keys, values)
+}
 
-	for _, key := range keyList {
-		child, ok := node.children[key]
-		if !ok {
-			if isRegex {
-				break
-			} else {
-				return
-			}
+func (n *Node) match(path string, keys, values []string) (*Node, []string, []string, bool) {
+	switch n.nType {
+	case ntStatic:
+		if !strings.HasPrefix(path, n.prefix) {
+			return nil, keys, values, false
 		}
-		if pattern == child.path && !isRegex {
-			nodes = append(nodes, child)
-			return
+		path = path[len(n.prefix):]
+	case ntParam:
+		seg, rest := splitSegment(path)
+		if seg == "" {
+			return nil, keys, values, false
 		}
-		node = child
+		keys = append(keys, n.paramName)
+		values = append(values, seg)
+		path = rest
+	case ntRegexp:
+		seg, rest := splitSegment(path)
+		if seg == "" || !n.regex.MatchString(seg) {
+			return nil, keys, values, false
+		}
+		keys = append(keys, n.paramName)
+		values = append(values, seg)
+		path = rest
+	case ntCatchAll:
+		keys = append(keys, n.paramName)
+		values = append(values, path)
+		return n, keys, values, true
 	}
 
-	queue = append(queue, node)
+	if path == "" {
+		if n.methods != nil || n.mounted != nil {
+			return n, keys, values, true
+		}
+		if n.anyChild != nil {
+			return n.anyChild, append(keys, n.anyChild.paramName), append(values, ""), true
+		}
+		return nil, keys, values, false
+	}
 
-	for len(queue) > 0 {
-		var queueTemp []*Node
-		for _, n := range queue {
-			if n.isEnd {
-				nodes = append(nodes, n)
-			}
-			for _, childNode := range n.children {
-				queueTemp = append(queueTemp, childNode)
-			}
+	if child, ok := n.children[path[0]]; ok {
+		if node, k, v, ok := child.match(path, keys, values); ok {
+			return node, k, v, true
+		}
+	}
+	for _, rc := range n.regexChildren {
+		if node, k, v, ok := rc.match(path, keys, values); ok {
+			return node, k, v, true
+		}
+	}
+	if n.paramChild != nil {
+		if node, k, v, ok := n.paramChild.match(path, keys, values); ok {
+			return node, k, v, true
 		}
-		queue = queueTemp
+	}
+	if n.anyChild != nil {
+		return n.anyChild, append(keys, n.anyChild.paramName), append(values, path), true
 	}
 
-	return
+	return nil, keys, values, false
+}
+
+// splitSegment splits path at its first '/' into the current segment and
+// the remainder (remainder keeps the leading '/', or is empty at the end).
+func splitSegment(path string) (segment, rest string) {
+	idx := strings.IndexByte(path, '/')
+	if idx == -1 {
+		return path, ""
+	}
+	return path[:idx], path[idx:]
+}
+
+// commonPrefixLen returns the length of the longest common prefix of a and b.
+func commonPrefixLen(a, b string) int {
+	max := len(a)
+	if len(b) < max {
+		max = len(b)
+	}
+	i := 0
+	for i < max && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// countParams counts the wildcard segments (":name", "{name:pattern}",
+// "*name") in pattern.
+func countParams(pattern string) int {
+	n := 0
+	for _, seg := range splitPattern(pattern) {
+		if seg == "" {
+			continue
+		}
+		switch seg[0] {
+		case ':', '{', '*':
+			n++
+		}
+	}
+	return n
 }
 
 // trimPathPrefix removes the prefix symbol '/' of pattern string.
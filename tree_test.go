@@ -0,0 +1,98 @@
+package krouter
+
+import (
+	"net/http"
+	"testing"
+)
+
+// @Author KHighness
+// @Update 2022-11-15
+
+func TestTreeMatchStaticParamRegexpCatchAll(t *testing.T) {
+	tree := NewTree()
+	handle := func(w http.ResponseWriter, r *http.Request) {}
+	tree.Register(http.MethodGet, "/user/list", handle)
+	tree.Register(http.MethodGet, "/user/:id", handle)
+	tree.Register(http.MethodGet, "/user/{name:[a-z]+}/repo", handle)
+	tree.Register(http.MethodGet, "/static/*filepath", handle)
+
+	cases := []struct {
+		path      string
+		wantMatch bool
+		wantKey   string
+		wantValue string
+	}{
+		{"/user/list", true, "", ""},
+		{"/user/42", true, "id", "42"},
+		{"/user/bob/repo", true, "name", "bob"},
+		{"/user/Bob/repo", false, "", ""},
+		{"/static/a/b/c.js", true, "filepath", "a/b/c.js"},
+		{"/nope", false, "", ""},
+	}
+
+	for _, c := range cases {
+		keys := make([]string, 0, tree.maxParams)
+		values := make([]string, 0, tree.maxParams)
+		node, keys, values, matched := tree.Match(c.path, keys, values)
+		if matched != c.wantMatch {
+			t.Errorf("%s: matched = %v, want %v", c.path, matched, c.wantMatch)
+			continue
+		}
+		if !matched {
+			continue
+		}
+		if node.methods == nil || node.methods.get == nil {
+			t.Errorf("%s: matched node has no GET handler", c.path)
+		}
+		if c.wantKey == "" {
+			if len(keys) != 0 {
+				t.Errorf("%s: keys = %v, want none", c.path, keys)
+			}
+			continue
+		}
+		found := false
+		for i, key := range keys {
+			if key == c.wantKey && values[i] == c.wantValue {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: keys=%v values=%v, want %s=%s", c.path, keys, values, c.wantKey, c.wantValue)
+		}
+	}
+}
+
+func TestTreeStaticPrefersMoreSpecificRoute(t *testing.T) {
+	tree := NewTree()
+	handle := func(w http.ResponseWriter, r *http.Request) {}
+	tree.Register(http.MethodGet, "/user/:id", handle)
+	tree.Register(http.MethodGet, "/user/me", handle)
+
+	keys := make([]string, 0, tree.maxParams)
+	values := make([]string, 0, tree.maxParams)
+	node, _, _, matched := tree.Match("/user/me", keys, values)
+	if !matched {
+		t.Fatal("expected /user/me to match")
+	}
+	if node.path != "user/me" {
+		t.Errorf("expected the static /user/me route to win over :id, matched path %q", node.path)
+	}
+}
+
+func TestInsertRegexpRejectsMalformedSegments(t *testing.T) {
+	cases := []string{
+		"/user/{id:[0-9]+", // missing closing '}'
+		"/user/{id}",       // missing ":pattern"
+	}
+	for _, pattern := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic for a malformed regexp segment", pattern)
+				}
+			}()
+			tree := NewTree()
+			tree.Register(http.MethodGet, pattern, func(w http.ResponseWriter, r *http.Request) {})
+		}()
+	}
+}